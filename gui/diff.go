@@ -0,0 +1,493 @@
+package gui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell"
+)
+
+// PatchOp is a single RFC 6902 JSON Patch operation.
+type PatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// diffStatus marks how a rendered diff node relates to the two documents
+// being compared.
+type diffStatus int
+
+const (
+	diffUnchanged diffStatus = iota
+	diffAdded
+	diffRemoved
+	diffChanged
+	diffConflict
+)
+
+// StructuralDiff recursively compares a ("ours"/base) against b ("theirs")
+// and returns the RFC 6902 patch that turns a into b. Objects are matched
+// by key, arrays by index.
+func StructuralDiff(a, b interface{}) []PatchOp {
+	return diffAt(a, b, "")
+}
+
+func diffAt(a, b interface{}, path string) []PatchOp {
+	switch bv := b.(type) {
+	case map[string]interface{}:
+		av, ok := a.(map[string]interface{})
+		if !ok {
+			return []PatchOp{{Op: "replace", Path: path, Value: b}}
+		}
+		return diffObjects(av, bv, path)
+	case []interface{}:
+		av, ok := a.([]interface{})
+		if !ok {
+			return []PatchOp{{Op: "replace", Path: path, Value: b}}
+		}
+		return diffArrays(av, bv, path)
+	default:
+		if !jsonEqual(a, b) {
+			return []PatchOp{{Op: "replace", Path: path, Value: b}}
+		}
+		return nil
+	}
+}
+
+func diffObjects(a, b map[string]interface{}, path string) []PatchOp {
+	var ops []PatchOp
+
+	for key, bv := range b {
+		childPath := path + "/" + jsonPointerEscape(key)
+		av, existed := a[key]
+		if !existed {
+			ops = append(ops, PatchOp{Op: "add", Path: childPath, Value: bv})
+			continue
+		}
+		ops = append(ops, diffAt(av, bv, childPath)...)
+	}
+
+	for key := range a {
+		if _, stillPresent := b[key]; !stillPresent {
+			ops = append(ops, PatchOp{Op: "remove", Path: path + "/" + jsonPointerEscape(key)})
+		}
+	}
+
+	return ops
+}
+
+func diffArrays(a, b []interface{}, path string) []PatchOp {
+	var ops []PatchOp
+
+	for i, bv := range b {
+		childPath := fmt.Sprintf("%s/%d", path, i)
+		if i >= len(a) {
+			ops = append(ops, PatchOp{Op: "add", Path: childPath, Value: bv})
+			continue
+		}
+		ops = append(ops, diffAt(a[i], bv, childPath)...)
+	}
+
+	// Remove from the end so each index still refers to the original
+	// element when the ops are applied in order.
+	for i := len(a) - 1; i >= len(b); i-- {
+		ops = append(ops, PatchOp{Op: "remove", Path: fmt.Sprintf("%s/%d", path, i)})
+	}
+
+	return ops
+}
+
+func jsonEqual(a, b interface{}) bool {
+	ab, _ := json.Marshal(a)
+	bb, _ := json.Marshal(b)
+	return bytes.Equal(ab, bb)
+}
+
+func jsonPointerEscape(s string) string {
+	s = strings.ReplaceAll(s, "~", "~0")
+	s = strings.ReplaceAll(s, "/", "~1")
+	return s
+}
+
+// DiffJSON loads the JSON document at path and replaces the tree with that
+// document, coloring the nodes it adds or changes relative to the current
+// document. Removed nodes are not part of the rendered document, so they
+// are not shown.
+func (g *Gui) DiffJSON(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		log.Println(fmt.Sprintf("can't open file: %s", err))
+		return err
+	}
+	defer file.Close()
+
+	theirs, err := UnMarshalJSON(file)
+	if err != nil {
+		return err
+	}
+
+	ours := g.makeJSON(g.Tree.GetRoot())
+	ops := StructuralDiff(ours, theirs)
+	g.lastDiffOps = ops
+
+	root := NewRootTreeNode(theirs)
+	root.SetChildren(g.Tree.AddNode(theirs))
+	g.Tree.SetRoot(root)
+	g.Tree.assignPaths(root, "$")
+	g.Tree.OriginRoot = root
+
+	colorDiff(g.Tree, ops)
+
+	return nil
+}
+
+// colorDiff colors the node at each op's path (an RFC 6901 pointer) to
+// reflect its add/remove/replace status; an op whose path has no node in
+// the tree (such as a remove, after DiffJSON) colors nothing. It looks each
+// path up through Tree.FindByPath rather than walking the whole tree, so coloring a diff
+// only materializes the handful of nodes the diff actually touches, not
+// every collapsed subtree along the way.
+func colorDiff(t *Tree, ops []PatchOp) {
+	for _, op := range ops {
+		path, err := normalizePath(op.Path)
+		if err != nil {
+			log.Println(fmt.Sprintf("can't normalize diff path %q: %s", op.Path, err))
+			continue
+		}
+
+		for _, node := range t.FindByPath(path) {
+			switch op.Op {
+			case "add":
+				node.SetColor(tcell.ColorGreen)
+			case "remove":
+				node.SetColor(tcell.ColorRed)
+			case "replace":
+				node.SetColor(tcell.ColorYellow)
+			}
+		}
+	}
+}
+
+// SaveJSONPatch writes ops (e.g. the patch from the most recent DiffJSON,
+// which turns the document diffed from into the file diffed against) out
+// to a file as indented JSON.
+func (g *Gui) SaveJSONPatch(ops []PatchOp, path string) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(ops); err != nil {
+		log.Println(fmt.Sprintf("can't marshal patch: %s", err))
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0666); err != nil {
+		log.Println(fmt.Sprintf("can't create file: %s", err))
+		return err
+	}
+
+	return nil
+}
+
+// ApplyJSONPatch reads an RFC 6902 patch file and applies it to the
+// current tree by reloading the patched document.
+func (g *Gui) ApplyJSONPatch(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Println(fmt.Sprintf("can't read patch file: %s", err))
+		return err
+	}
+
+	var ops []PatchOp
+	if err := json.Unmarshal(b, &ops); err != nil {
+		log.Println(fmt.Sprintf("can't parse patch file: %s", err))
+		return err
+	}
+
+	doc := g.makeJSON(g.Tree.GetRoot())
+	patched, err := applyPatch(doc, ops)
+	if err != nil {
+		return err
+	}
+
+	g.Tree.UpdateView(g, patched)
+	return nil
+}
+
+func applyPatch(doc interface{}, ops []PatchOp) (interface{}, error) {
+	for _, op := range ops {
+		var err error
+		doc, err = applyOp(doc, op)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// applyOp applies a single RFC 6902 operation to doc, following its JSON
+// Pointer path and mutating the containing map/slice in place.
+func applyOp(doc interface{}, op PatchOp) (interface{}, error) {
+	tokens := pointerTokens(op.Path)
+	if len(tokens) == 0 {
+		switch op.Op {
+		case "add", "replace":
+			return op.Value, nil
+		case "remove":
+			return nil, nil
+		}
+		return doc, nil
+	}
+
+	return setAtPointer(doc, tokens, op)
+}
+
+func pointerTokens(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return nil
+	}
+
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		p = strings.ReplaceAll(p, "~1", "/")
+		p = strings.ReplaceAll(p, "~0", "~")
+		parts[i] = p
+	}
+	return parts
+}
+
+// setAtPointer applies op at the location described by tokens, recursing
+// into doc one token at a time and mutating the parent container once the
+// final token is reached.
+func setAtPointer(doc interface{}, tokens []string, op PatchOp) (interface{}, error) {
+	key := tokens[0]
+
+	if len(tokens) == 1 {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			switch op.Op {
+			case "add", "replace":
+				v[key] = op.Value
+			case "remove":
+				delete(v, key)
+			}
+			return v, nil
+		case []interface{}:
+			// "-" addresses the position past the last element (append).
+			if key == "-" && op.Op == "add" {
+				return append(v, op.Value), nil
+			}
+			idx, err := strconv.Atoi(key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid array index %q in path %q", key, op.Path)
+			}
+			switch op.Op {
+			case "add":
+				if idx < 0 || idx > len(v) {
+					return nil, fmt.Errorf("index %d out of range for path %q", idx, op.Path)
+				}
+				v = append(v[:idx:idx], append([]interface{}{op.Value}, v[idx:]...)...)
+			case "replace":
+				if idx < 0 || idx >= len(v) {
+					return nil, fmt.Errorf("index %d out of range for path %q", idx, op.Path)
+				}
+				v[idx] = op.Value
+			case "remove":
+				if idx < 0 || idx >= len(v) {
+					return nil, fmt.Errorf("index %d out of range for path %q", idx, op.Path)
+				}
+				v = append(v[:idx], v[idx+1:]...)
+			}
+			return v, nil
+		default:
+			return nil, fmt.Errorf("can't apply %q at non-container path %q", op.Op, op.Path)
+		}
+	}
+
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		child, ok := v[key]
+		if !ok {
+			return nil, fmt.Errorf("path not found: %q", op.Path)
+		}
+		newChild, err := setAtPointer(child, tokens[1:], op)
+		if err != nil {
+			return nil, err
+		}
+		v[key] = newChild
+		return v, nil
+	case []interface{}:
+		idx, err := strconv.Atoi(key)
+		if err != nil || idx < 0 || idx >= len(v) {
+			return nil, fmt.Errorf("invalid array index %q in path %q", key, op.Path)
+		}
+		newChild, err := setAtPointer(v[idx], tokens[1:], op)
+		if err != nil {
+			return nil, err
+		}
+		v[idx] = newChild
+		return v, nil
+	default:
+		return nil, fmt.Errorf("path not found: %q", op.Path)
+	}
+}
+
+// MergeJSON performs a three-way merge of base against ours (the current
+// tree) and theirs (loaded from path), producing a conflict-annotated tree
+// the user can resolve before saving. A conflict is any path changed by
+// both ours and theirs to different values.
+func (g *Gui) MergeJSON(basePath, theirsPath string) error {
+	base, err := loadJSONFile(basePath)
+	if err != nil {
+		return err
+	}
+	theirs, err := loadJSONFile(theirsPath)
+	if err != nil {
+		return err
+	}
+	ours := g.makeJSON(g.Tree.GetRoot())
+
+	oursOps := StructuralDiff(base, ours)
+	theirsOps := StructuralDiff(base, theirs)
+
+	merged, conflicts := mergeOps(base, oursOps, theirsOps)
+
+	root := NewRootTreeNode(merged)
+	root.SetChildren(g.Tree.AddNode(merged))
+	g.Tree.SetRoot(root)
+	g.Tree.assignPaths(root, "$")
+	g.Tree.OriginRoot = root
+
+	markConflicts(g.Tree, conflicts)
+
+	return nil
+}
+
+func loadJSONFile(path string) (interface{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		log.Println(fmt.Sprintf("can't open file: %s", err))
+		return nil, err
+	}
+	defer file.Close()
+	return UnMarshalJSON(file)
+}
+
+// mergeOps applies every non-conflicting op from ours and theirs onto
+// base, preferring ours' value at conflicting paths and reporting the
+// JSON Pointer of each conflict for the caller to annotate.
+func mergeOps(base interface{}, oursOps, theirsOps []PatchOp) (interface{}, []string) {
+	theirsByPath := make(map[string]PatchOp)
+	for _, op := range theirsOps {
+		theirsByPath[op.Path] = op
+	}
+
+	var conflicts []string
+	merged, _ := applyPatch(base, oursOps)
+
+	for _, op := range oursOps {
+		if theirOp, ok := theirsByPath[op.Path]; ok && !patchEqual(op, theirOp) {
+			conflicts = append(conflicts, op.Path)
+		}
+	}
+	for _, op := range theirsOps {
+		if _, oursTouched := findOp(oursOps, op.Path); !oursTouched {
+			merged, _ = applyOp(merged, op)
+		}
+	}
+
+	return merged, conflicts
+}
+
+func findOp(ops []PatchOp, path string) (PatchOp, bool) {
+	for _, op := range ops {
+		if op.Path == path {
+			return op, true
+		}
+	}
+	return PatchOp{}, false
+}
+
+func patchEqual(a, b PatchOp) bool {
+	return a.Op == b.Op && jsonEqual(a.Value, b.Value)
+}
+
+// markConflicts colors the node at each conflicting path orange. Like
+// colorDiff, it resolves each path through Tree.FindByPath instead of
+// walking the whole tree, so marking a merge's conflicts only materializes
+// the nodes actually in conflict.
+func markConflicts(t *Tree, conflicts []string) {
+	for _, c := range conflicts {
+		path, err := normalizePath(c)
+		if err != nil {
+			log.Println(fmt.Sprintf("can't normalize conflict path %q: %s", c, err))
+			continue
+		}
+
+		for _, node := range t.FindByPath(path) {
+			node.SetColor(tcell.ColorOrange)
+		}
+	}
+}
+
+// DiffJSONPrompt asks for a file to diff the current tree against.
+func (g *Gui) DiffJSONPrompt() {
+	labels := []string{"file"}
+	g.Form(labels, "diff", "diff against file", "diff_json", 7, func(values map[string]string) error {
+		return g.DiffJSON(values[labels[0]])
+	})
+}
+
+// SaveJSONPatchPrompt asks for a file to save the most recent DiffJSON's
+// patch to.
+func (g *Gui) SaveJSONPatchPrompt() {
+	if g.lastDiffOps == nil {
+		g.Message("run DiffJSON first", "main", func() {})
+		return
+	}
+
+	labels := []string{"file"}
+	g.Form(labels, "save", "save patch to file", "save_json_patch", 7, func(values map[string]string) error {
+		return g.SaveJSONPatch(g.lastDiffOps, values[labels[0]])
+	})
+}
+
+// ApplyJSONPatchPrompt asks for a patch file to apply to the current tree.
+func (g *Gui) ApplyJSONPatchPrompt() {
+	labels := []string{"file"}
+	g.Form(labels, "apply", "apply patch file", "apply_json_patch", 7, func(values map[string]string) error {
+		return g.ApplyJSONPatch(values[labels[0]])
+	})
+}
+
+// MergeJSONPrompt asks for a base and a "theirs" file to three-way merge
+// against the current tree ("ours").
+func (g *Gui) MergeJSONPrompt() {
+	labels := []string{"base", "theirs"}
+	g.Form(labels, "merge", "three-way merge", "merge_json", 8, func(values map[string]string) error {
+		return g.MergeJSON(values[labels[0]], values[labels[1]])
+	})
+}
+
+// jsonPathToPointer converts the internal "$.foo.bar[2]" canonical path
+// form into an RFC 6901 JSON Pointer ("/foo/bar/2").
+func jsonPathToPointer(path string) string {
+	normalized, err := normalizeJSONPath(path)
+	if err != nil {
+		normalized = path
+	}
+	rest := strings.TrimPrefix(normalized, "$")
+	rest = strings.ReplaceAll(rest, "][", "]/[")
+	rest = strings.ReplaceAll(rest, ".", "/")
+	rest = strings.ReplaceAll(rest, "[", "/")
+	rest = strings.ReplaceAll(rest, "]", "")
+	return rest
+}
@@ -0,0 +1,132 @@
+package gui
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func sortOps(ops []PatchOp) {
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Path != ops[j].Path {
+			return ops[i].Path < ops[j].Path
+		}
+		return ops[i].Op < ops[j].Op
+	})
+}
+
+func TestStructuralDiff(t *testing.T) {
+	a := map[string]interface{}{
+		"name":    "widget",
+		"count":   float64(1),
+		"removed": "bye",
+	}
+	b := map[string]interface{}{
+		"name":  "widget",
+		"count": float64(2),
+		"added": "hi",
+	}
+
+	ops := StructuralDiff(a, b)
+	sortOps(ops)
+
+	want := []PatchOp{
+		{Op: "add", Path: "/added", Value: "hi"},
+		{Op: "replace", Path: "/count", Value: float64(2)},
+		{Op: "remove", Path: "/removed"},
+	}
+	sortOps(want)
+
+	if !reflect.DeepEqual(ops, want) {
+		t.Errorf("got %+v, want %+v", ops, want)
+	}
+}
+
+func TestStructuralDiffArrays(t *testing.T) {
+	a := []interface{}{"a", "b", "c"}
+	b := []interface{}{"a", "x"}
+
+	ops := StructuralDiff(a, b)
+	sortOps(ops)
+
+	want := []PatchOp{
+		{Op: "remove", Path: "/2"},
+		{Op: "replace", Path: "/1", Value: "x"},
+	}
+	sortOps(want)
+
+	if !reflect.DeepEqual(ops, want) {
+		t.Errorf("got %+v, want %+v", ops, want)
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	doc := map[string]interface{}{
+		"name":  "widget",
+		"count": float64(1),
+		"tags":  []interface{}{"a", "b"},
+	}
+
+	ops := []PatchOp{
+		{Op: "replace", Path: "/count", Value: float64(2)},
+		{Op: "add", Path: "/tags/1", Value: "z"},
+		{Op: "remove", Path: "/name"},
+	}
+
+	patched, err := applyPatch(doc, ops)
+	if err != nil {
+		t.Fatalf("applyPatch: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"count": float64(2),
+		"tags":  []interface{}{"a", "z", "b"},
+	}
+
+	if !reflect.DeepEqual(patched, want) {
+		t.Errorf("got %+v, want %+v", patched, want)
+	}
+}
+
+func TestApplyPatchOutOfRangeArrayIndex(t *testing.T) {
+	doc := []interface{}{"a"}
+	_, err := applyPatch(doc, []PatchOp{{Op: "replace", Path: "/5", Value: "x"}})
+	if err == nil {
+		t.Fatal("expected an error for an out-of-range array index")
+	}
+}
+
+func TestMergeOpsNoConflict(t *testing.T) {
+	base := map[string]interface{}{"a": "1", "b": "1"}
+	ours := map[string]interface{}{"a": "2", "b": "1"}
+	theirs := map[string]interface{}{"a": "1", "b": "2"}
+
+	merged, conflicts := mergeOps(base, StructuralDiff(base, ours), StructuralDiff(base, theirs))
+
+	if len(conflicts) != 0 {
+		t.Fatalf("expected no conflicts, got %v", conflicts)
+	}
+
+	want := map[string]interface{}{"a": "2", "b": "2"}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("got %+v, want %+v", merged, want)
+	}
+}
+
+func TestMergeOpsConflict(t *testing.T) {
+	base := map[string]interface{}{"a": "1"}
+	ours := map[string]interface{}{"a": "2"}
+	theirs := map[string]interface{}{"a": "3"}
+
+	merged, conflicts := mergeOps(base, StructuralDiff(base, ours), StructuralDiff(base, theirs))
+
+	if len(conflicts) != 1 || conflicts[0] != "/a" {
+		t.Fatalf("expected a conflict at /a, got %v", conflicts)
+	}
+
+	// ours wins at a conflicting path.
+	want := map[string]interface{}{"a": "2"}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("got %+v, want %+v", merged, want)
+	}
+}
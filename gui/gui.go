@@ -14,6 +14,7 @@ import (
 
 	"github.com/gdamore/tcell"
 	"github.com/rivo/tview"
+	"github.com/santhosh-tekuri/jsonschema/v5"
 )
 
 var (
@@ -21,16 +22,43 @@ var (
 )
 
 type Gui struct {
-	Tree  *Tree
-	App   *tview.Application
-	Pages *tview.Pages
+	Tree      *Tree
+	App       *tview.Application
+	Pages     *tview.Pages
+	StatusBar *tview.TextView
+
+	// index is the Bleve-backed search index over Tree, rebuilt whenever
+	// the tree is mutated. It is nil until the first successful build,
+	// in which case Search falls back to walk.
+	index *SearchIndex
+
+	// lastDiffOps is the patch computed by the most recent DiffJSON call,
+	// kept around so SaveJSONPatchPrompt doesn't need to recompute it.
+	lastDiffOps []PatchOp
+
+	// schema and schemaErrors back JSON Schema validation (see schema.go).
+	// schema is nil until LoadSchema succeeds.
+	schema       *jsonschema.Schema
+	schemaErrors map[string]string
+
+	// Executor runs every tree mutation as a Command so it can be undone/
+	// redone (see undo.go), and persists each one to a change log next to
+	// sourcePath.
+	Executor   *Executor
+	sourcePath string
 }
 
+// historyCapacity bounds how many commands Executor.Undo can reach back
+// through.
+const historyCapacity = 100
+
 func New() *Gui {
 	g := &Gui{
-		Tree:  NewTree(),
-		App:   tview.NewApplication(),
-		Pages: tview.NewPages(),
+		Tree:      NewTree(),
+		App:       tview.NewApplication(),
+		Pages:     tview.NewPages(),
+		StatusBar: tview.NewTextView(),
+		Executor:  NewExecutor(historyCapacity),
 	}
 	return g
 }
@@ -40,7 +68,9 @@ func (g *Gui) Run(i interface{}) error {
 	g.Tree.SetKeybindings(g)
 
 	grid := tview.NewGrid().
-		AddItem(g.Tree, 0, 0, 1, 1, 0, 0, true)
+		SetRows(0, 1).
+		AddItem(g.Tree, 0, 0, 1, 1, 0, 0, true).
+		AddItem(g.StatusBar, 1, 0, 1, 1, 0, 0, false)
 
 	g.Pages.AddAndSwitchToPage("main", grid, true)
 
@@ -147,6 +177,21 @@ func (g *Gui) LoadJSON() {
 			return err
 		}
 
+		if ops, err := recoverChangeLog(fileName); err != nil {
+			log.Println(fmt.Sprintf("can't read change log for %s: %s", fileName, err))
+		} else if len(ops) > 0 {
+			if replayed, err := applyPatch(i, ops); err != nil {
+				log.Println(fmt.Sprintf("can't replay change log for %s: %s", fileName, err))
+			} else {
+				i = replayed
+				log.Println(fmt.Sprintf("replayed %d change(s) from a previous session", len(ops)))
+			}
+		}
+		os.Remove(fileName + undoLogSuffix)
+
+		g.sourcePath = fileName
+		g.Executor.Reset()
+		g.Executor.openLog(fileName)
 		g.Tree.UpdateView(g, i)
 		return nil
 	})
@@ -162,10 +207,21 @@ func (g *Gui) Search() {
 		input.SetChangedFunc(func(text string) {
 			root := *g.Tree.OriginRoot
 			g.Tree.SetRoot(&root)
-			if text != "" {
-				root := g.Tree.GetRoot()
-				root.SetChildren(g.walk(root.GetChildren(), text))
+			if text == "" {
+				return
+			}
+
+			if g.index != nil {
+				if nodes, err := g.index.Query(text); err == nil {
+					newRoot := g.Tree.GetRoot()
+					newRoot.SetChildren(nodes)
+					return
+				}
+				log.Println(fmt.Sprintf("search index query failed, falling back to walk: %s", text))
 			}
+
+			newRoot := g.Tree.GetRoot()
+			newRoot.SetChildren(g.walk(newRoot.GetChildren(), text))
 		})
 		input.SetLabel("word").SetLabelWidth(5).SetDoneFunc(func(key tcell.Key) {
 			if key == tcell.KeyEnter {
@@ -217,36 +273,62 @@ func (g *Gui) SaveJSON() {
 }
 
 func (g *Gui) makeJSON(node *tview.TreeNode) interface{} {
+	return nodeToJSON(node)
+}
+
+// nodeToJSON reconstructs the decoded JSON value rooted at node, including
+// subtrees that haven't been materialized yet (see Tree.expand) or were
+// evicted to a spill file under memory pressure.
+func nodeToJSON(node *tview.TreeNode) interface{} {
 	ref := node.GetReference().(Reference)
+
+	if (ref.JSONType == Object || ref.JSONType == Array) && !ref.Loaded {
+		v, err := pendingValue(ref)
+		if err != nil {
+			log.Println(fmt.Sprintf("can't reconstitute evicted subtree at %s: %s", ref.Path, err))
+			return nil
+		}
+		return v
+	}
+
 	children := node.GetChildren()
 
 	switch ref.JSONType {
 	case Object:
 		i := make(map[string]interface{})
 		for _, n := range children {
-			i[n.GetText()] = g.makeJSON(n)
+			v := n
+			if ref, _ := n.GetReference().(Reference); ref.JSONType == Key {
+				// A Key node's value is its single child (see Tree.AddNode).
+				v = n.GetChildren()[0]
+			}
+			i[n.GetText()] = nodeToJSON(v)
 		}
 		return i
 	case Array:
 		var i []interface{}
 		for _, n := range children {
-			i = append(i, g.makeJSON(n))
+			i = append(i, nodeToJSON(n))
 		}
 		return i
 	case Key:
 		v := node.GetChildren()[0]
 		if v.GetReference().(Reference).JSONType == Value {
-			return g.parseValue(v)
+			return nodeValue(v)
 		}
 		return map[string]interface{}{
-			node.GetText(): g.makeJSON(v),
+			node.GetText(): nodeToJSON(v),
 		}
 	}
 
-	return g.parseValue(node)
+	return nodeValue(node)
 }
 
 func (g *Gui) parseValue(node *tview.TreeNode) interface{} {
+	return nodeValue(node)
+}
+
+func nodeValue(node *tview.TreeNode) interface{} {
 	v := node.GetText()
 	ref := node.GetReference().(Reference)
 
@@ -284,11 +366,16 @@ func (g *Gui) AddNode() {
 
 		newNode := NewRootTreeNode(i)
 		newNode.SetChildren(g.Tree.AddNode(i))
-		g.Tree.GetCurrentNode().AddChild(newNode)
-		// update new origin root node
-		g.Tree.OriginRoot = g.Tree.GetRoot()
 
-		return nil
+		parent := g.Tree.GetCurrentNode()
+		parentRef, _ := parent.GetReference().(Reference)
+
+		return g.runCommand(&addNodesCommand{
+			parent:   parent,
+			children: []*tview.TreeNode{newNode},
+			values:   []interface{}{nodeToJSON(newNode)},
+			path:     jsonPathToPointer(parentRef.Path),
+		})
 	})
 }
 
@@ -308,30 +395,79 @@ func (g *Gui) AddValue() {
 		}
 
 		current := g.Tree.GetCurrentNode()
-		for _, n := range g.Tree.AddNode(i) {
-			current.AddChild(n)
+		currentRef, _ := current.GetReference().(Reference)
+
+		children := g.Tree.AddNode(i)
+		nodeValues := make([]interface{}, len(children))
+		for idx, n := range children {
+			nodeValues[idx] = nodeToJSON(n)
 		}
-		// update new origin root node
-		g.Tree.OriginRoot = g.Tree.GetRoot()
 
-		return nil
+		return g.runCommand(&addNodesCommand{
+			parent:   current,
+			children: children,
+			values:   nodeValues,
+			path:     jsonPathToPointer(currentRef.Path),
+		})
 	})
 
 }
 
-func UnMarshalJSON(in io.Reader) (interface{}, error) {
-	b, err := ioutil.ReadAll(in)
-	if err != nil {
-		log.Println(err)
-		return nil, err
+// runCommand runs cmd through g.Executor and refreshes the derived state
+// (origin root, search index, node paths, schema decoration) that every
+// tree mutation needs to stay consistent.
+func (g *Gui) runCommand(cmd Command) error {
+	if err := g.Executor.Do(cmd); err != nil {
+		return err
 	}
-	if len(b) == 0 {
-		log.Println(err)
-		return nil, ErrEmptyJSON
+
+	g.Tree.OriginRoot = g.Tree.GetRoot()
+	g.rebuildIndex()
+	g.validateSchema()
+
+	return nil
+}
+
+// Undo reverts the last command run through g.Executor, wired to Ctrl-Z in
+// Tree.SetKeybindings. It returns ErrNoHistory when there's nothing to undo.
+func (g *Gui) Undo() error {
+	if err := g.Executor.Undo(); err != nil {
+		return err
+	}
+
+	g.Tree.OriginRoot = g.Tree.GetRoot()
+	g.rebuildIndex()
+	g.validateSchema()
+
+	return nil
+}
+
+// Redo re-applies the last command undone via g.Undo, wired to Ctrl-R in
+// Tree.SetKeybindings. It returns ErrNoHistory when there's nothing to redo.
+func (g *Gui) Redo() error {
+	if err := g.Executor.Redo(); err != nil {
+		return err
 	}
 
+	g.Tree.OriginRoot = g.Tree.GetRoot()
+	g.rebuildIndex()
+	g.validateSchema()
+
+	return nil
+}
+
+// UnMarshalJSON decodes in via json.Decoder rather than buffering the
+// whole input with ioutil.ReadAll first. The result is still a single
+// in-memory interface{}; Tree.AddNode is what keeps multi-hundred-MB
+// documents from OOMing, by only materializing a subtree's *tview.TreeNode
+// children once the user expands it (see lazy.go).
+func UnMarshalJSON(in io.Reader) (interface{}, error) {
 	var i interface{}
-	if err := json.Unmarshal(b, &i); err != nil {
+	if err := json.NewDecoder(in).Decode(&i); err != nil {
+		if err == io.EOF {
+			log.Println(ErrEmptyJSON)
+			return nil, ErrEmptyJSON
+		}
 		log.Println(err)
 		return nil, err
 	}
@@ -0,0 +1,183 @@
+package gui
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/rivo/tview"
+)
+
+// indexDoc is the document stored in the Bleve index for every value in the
+// tree's decoded JSON, keyed by its canonical JSON path. Numeric is only set
+// for scalar int/float values, so numericRange queries (see Query) don't
+// have to treat every other value's zero value as a match.
+type indexDoc struct {
+	Path    string   `json:"path"`
+	Key     string   `json:"key"`
+	Value   string   `json:"value"`
+	Type    string   `json:"type"`
+	Numeric *float64 `json:"numeric,omitempty"`
+}
+
+// SearchIndex is a build-once, query-many Bleve index over a Tree's decoded
+// JSON. It is rebuilt whenever the tree is mutated (LoadJSON, AddNode,
+// AddValue) and is nil until the first build succeeds, so callers must fall
+// back to a manual walk when it isn't available.
+type SearchIndex struct {
+	index bleve.Index
+	t     *Tree
+}
+
+// BuildIndex indexes every value reachable from t's root, keyed by its
+// canonical JSON path. It walks the root's decoded JSON (via nodeToJSON,
+// which already knows how to read a not-yet-materialized Object/Array's
+// pending or spilled value) rather than node.GetChildren(), so building the
+// index never forces a collapsed subtree to materialize its *tview.TreeNode
+// children: resolving a match back to a node (see nodesFor) expands only
+// the path actually matched, on demand.
+func BuildIndex(t *Tree) (*SearchIndex, error) {
+	mapping := bleve.NewIndexMapping()
+	index, err := bleve.NewMemOnly(mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	si := &SearchIndex{index: index, t: t}
+
+	root := t.GetRoot()
+	if root == nil {
+		return si, nil
+	}
+
+	for _, doc := range indexDocsFor(nodeToJSON(root), "$") {
+		if err := si.index.Index(doc.Path, doc); err != nil {
+			return nil, err
+		}
+	}
+
+	return si, nil
+}
+
+// indexDocsFor recursively builds the indexDoc for v (at path) and every
+// value nested under it, mirroring the path algorithm Tree.assignPaths uses
+// for rendered nodes so a hit's Path resolves back to the same node via
+// Tree.FindByPath.
+func indexDocsFor(v interface{}, path string) []indexDoc {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		docs := []indexDoc{{Path: path, Key: "{...}", Value: "{...}", Type: "object"}}
+		for key, val := range vv {
+			docs = append(docs, indexDocsFor(val, fmt.Sprintf("%s.%s", path, key))...)
+		}
+		return docs
+	case []interface{}:
+		docs := []indexDoc{{Path: path, Key: "[...]", Value: "[...]", Type: "array"}}
+		for i, val := range vv {
+			docs = append(docs, indexDocsFor(val, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return docs
+	default:
+		text := fmt.Sprintf("%v", vv)
+		return []indexDoc{{Path: path, Key: text, Value: text, Type: "value", Numeric: numericValue(vv)}}
+	}
+}
+
+// numericValue returns v as a float64 for the index's numeric field, or nil
+// if v isn't a JSON number.
+func numericValue(v interface{}) *float64 {
+	switch n := v.(type) {
+	case float64:
+		return &n
+	case int:
+		f := float64(n)
+		return &f
+	}
+	return nil
+}
+
+// numericRangeExpr matches a bare "min..max" expression, either bound
+// optional, e.g. "10..20", "10..", "..20".
+var numericRangeExpr = regexp.MustCompile(`^(-?\d+(?:\.\d+)?)?\.\.(-?\d+(?:\.\d+)?)?$`)
+
+// Query runs expr against the index and returns the matching nodes. expr is
+// normally a Bleve query string (so prefix/phrase/field:term syntax all
+// work), but a bare "min..max" expression is treated as a numeric range
+// query over every indexed number, built with bleve.NewNumericRangeQuery.
+func (si *SearchIndex) Query(expr string) ([]*tview.TreeNode, error) {
+	if m := numericRangeExpr.FindStringSubmatch(expr); m != nil {
+		return si.queryNumericRange(parseBound(m[1]), parseBound(m[2]))
+	}
+
+	req := bleve.NewSearchRequest(bleve.NewQueryStringQuery(expr))
+	req.Size = 1000
+
+	result, err := si.index.Search(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return si.nodesFor(result), nil
+}
+
+func (si *SearchIndex) queryNumericRange(min, max *float64) ([]*tview.TreeNode, error) {
+	query := bleve.NewNumericRangeQuery(min, max)
+	query.SetField("numeric")
+
+	req := bleve.NewSearchRequest(query)
+	req.Size = 1000
+
+	result, err := si.index.Search(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return si.nodesFor(result), nil
+}
+
+func parseBound(s string) *float64 {
+	if s == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
+
+// nodesFor resolves each hit's path back to a live *tview.TreeNode, via
+// Tree.FindByPath. This is the only point where a matched subtree gets
+// materialized: FindByPath only expands the ancestors of the specific path
+// it's looking for, so a search that matches a handful of nodes deep inside
+// a huge document still doesn't force the rest of it into memory.
+func (si *SearchIndex) nodesFor(result *bleve.SearchResult) []*tview.TreeNode {
+	var nodes []*tview.TreeNode
+	for _, hit := range result.Hits {
+		nodes = append(nodes, si.t.FindByPath(hit.ID)...)
+	}
+	return nodes
+}
+
+// rebuildIndex recomputes every materialized node's canonical path and
+// rebuilds g.index from the current tree, logging (but not failing the
+// calling mutation) if the index can't be built.
+func (g *Gui) rebuildIndex() {
+	root := g.Tree.GetRoot()
+	if root == nil {
+		return
+	}
+
+	g.Tree.assignPaths(root, "$")
+
+	si, err := BuildIndex(g.Tree)
+	if err != nil {
+		log.Println(fmt.Sprintf("can't build search index: %s", err))
+		g.index = nil
+		return
+	}
+
+	g.index = si
+}
@@ -0,0 +1,101 @@
+package gui
+
+import (
+	"testing"
+
+	"github.com/rivo/tview"
+)
+
+// valueChild returns the single Value/Object/Array child of the top-level
+// Key node named key, as built by Tree.AddNode/newValueNode.
+func valueChild(root *tview.TreeNode, key string) *tview.TreeNode {
+	for _, keyNode := range root.GetChildren() {
+		if keyNode.GetText() == key {
+			return keyNode.GetChildren()[0]
+		}
+	}
+	return nil
+}
+
+func TestSearchIndexQuery(t *testing.T) {
+	g := New()
+	doc := map[string]interface{}{
+		"name":  "widget",
+		"count": float64(5),
+	}
+	g.Tree.UpdateView(g, doc)
+
+	nodes, err := g.index.Query("widget")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(nodes) == 0 {
+		t.Fatal(`expected at least one match for "widget"`)
+	}
+}
+
+// TestSearchIndexNumericRangeQuery exercises the "min..max" syntax backed by
+// bleve.NewNumericRangeQuery over the index's dedicated numeric field.
+func TestSearchIndexNumericRangeQuery(t *testing.T) {
+	g := New()
+	doc := map[string]interface{}{
+		"low":  float64(1),
+		"mid":  float64(5),
+		"high": float64(100),
+	}
+	g.Tree.UpdateView(g, doc)
+
+	nodes, err := g.index.Query("0..10")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+
+	got := make(map[string]bool)
+	for _, n := range nodes {
+		got[n.GetText()] = true
+	}
+	if !got["1"] || !got["5"] {
+		t.Errorf("expected 1 and 5 in range 0..10, got %v", got)
+	}
+	if got["100"] {
+		t.Errorf("100 should not match range 0..10")
+	}
+}
+
+// TestSearchIndexLazySubtreeIsIndexed guards against BuildIndex only seeing
+// whatever happens to already be expanded: "outer" is a lazy placeholder
+// until something expands it, so indexing off node.GetChildren() would
+// never reach "inner". BuildIndex instead indexes off the tree's decoded
+// JSON (see nodeToJSON), so the match is found without materializing
+// anything, and only the path actually matched (under "outer") gets
+// expanded when the caller resolves it to a node — "decoy" must stay
+// untouched.
+func TestSearchIndexLazySubtreeIsIndexed(t *testing.T) {
+	g := New()
+	doc := map[string]interface{}{
+		"outer": map[string]interface{}{
+			"inner": "findme",
+		},
+		"decoy": map[string]interface{}{
+			"other": "untouched",
+		},
+	}
+	g.Tree.UpdateView(g, doc)
+
+	root := g.Tree.GetRoot()
+	if ref, _ := valueChild(root, "outer").GetReference().(Reference); ref.Loaded {
+		t.Fatal(`"outer" should still be a lazy placeholder right after BuildIndex`)
+	}
+
+	nodes, err := g.index.Query("findme")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(nodes) == 0 {
+		t.Fatal(`expected a match inside the not-yet-expanded "outer" subtree`)
+	}
+
+	if ref, _ := valueChild(root, "decoy").GetReference().(Reference); ref.Loaded {
+		t.Error(`"decoy" should stay a lazy placeholder: resolving a match under "outer" shouldn't expand unrelated subtrees`)
+	}
+}
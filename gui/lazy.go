@@ -0,0 +1,132 @@
+package gui
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+
+	"github.com/rivo/tview"
+)
+
+// defaultMemoryFraction is the divisor applied to runtime.MemStats.Sys to
+// budget materialized subtrees when TSON_MEMORYLIMIT isn't set.
+const defaultMemoryFraction = 4
+
+// fallbackBudget is used when runtime.MemStats.Sys reports zero.
+const fallbackBudget = 256 << 20
+
+// lazyStore tracks which Object/Array nodes currently hold their real
+// children in memory, evicting the least-recently-viewed ones once the
+// configured byte budget is exceeded. The root node is never tracked
+// here, so it's never a candidate for eviction.
+type lazyStore struct {
+	mu      sync.Mutex
+	budget  int64
+	used    int64
+	order   *list.List
+	entries map[*tview.TreeNode]*list.Element
+}
+
+type lazyEntry struct {
+	node *tview.TreeNode
+	size int64
+}
+
+func newLazyStore() *lazyStore {
+	return &lazyStore{
+		budget:  memoryBudget(),
+		order:   list.New(),
+		entries: make(map[*tview.TreeNode]*list.Element),
+	}
+}
+
+// memoryBudget returns TSON_MEMORYLIMIT in bytes if set, otherwise 1/4 of
+// runtime.MemStats.Sys, the memory the Go runtime has obtained from the OS
+// at the time of the call. The machine's total RAM is not consulted.
+func memoryBudget() int64 {
+	if v := os.Getenv("TSON_MEMORYLIMIT"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			return n
+		}
+		log.Println(fmt.Sprintf("ignoring invalid TSON_MEMORYLIMIT=%q", v))
+	}
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	if m.Sys == 0 {
+		return fallbackBudget
+	}
+	return int64(m.Sys) / defaultMemoryFraction
+}
+
+// touch records that node now holds size bytes of materialized children,
+// evicting older entries (oldest first) until usage is back within
+// budget.
+func (s *lazyStore) touch(node *tview.TreeNode, size int64) {
+	s.mu.Lock()
+	var toEvict []*tview.TreeNode
+
+	if el, ok := s.entries[node]; ok {
+		s.order.MoveToFront(el)
+		s.used += size - el.Value.(*lazyEntry).size
+		el.Value.(*lazyEntry).size = size
+	} else {
+		el := s.order.PushFront(&lazyEntry{node: node, size: size})
+		s.entries[node] = el
+		s.used += size
+	}
+
+	for s.used > s.budget && s.order.Len() > 1 {
+		back := s.order.Back()
+		entry := back.Value.(*lazyEntry)
+		if entry.node == node {
+			break
+		}
+		toEvict = append(toEvict, entry.node)
+		s.used -= entry.size
+		s.order.Remove(back)
+		delete(s.entries, entry.node)
+	}
+	s.mu.Unlock()
+
+	for _, n := range toEvict {
+		evict(n)
+	}
+}
+
+// evict spills node's materialized children to a tempfile and collapses
+// it back to a lazily-reloadable placeholder.
+func evict(node *tview.TreeNode) {
+	ref, _ := node.GetReference().(Reference)
+
+	value := nodeToJSON(node)
+	b, err := json.Marshal(value)
+	if err != nil {
+		log.Println(fmt.Sprintf("can't spill subtree at %s: %s", ref.Path, err))
+		return
+	}
+
+	f, err := ioutil.TempFile("", "tson-spill-*.json")
+	if err != nil {
+		log.Println(fmt.Sprintf("can't create spill file for %s: %s", ref.Path, err))
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(b); err != nil {
+		log.Println(fmt.Sprintf("can't write spill file for %s: %s", ref.Path, err))
+		return
+	}
+
+	ref.Pending = nil
+	ref.SpillPath = f.Name()
+	ref.Loaded = false
+	node.SetReference(ref)
+	node.SetChildren([]*tview.TreeNode{placeholderNode()})
+}
@@ -0,0 +1,191 @@
+package gui
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+var jsonPathSegment = regexp.MustCompile(`\[(\d+)\]|\.([^.\[\]]+)`)
+
+// normalizePath accepts either a JSONPath expression ("$.foo.bar[2].baz")
+// or an RFC 6901 JSON Pointer ("/foo/bar/2/baz") and returns the internal
+// "$.foo.bar[2].baz" form that Reference.Path is stored in.
+func normalizePath(expr string) (string, error) {
+	switch {
+	case strings.HasPrefix(expr, "$"):
+		return normalizeJSONPath(expr)
+	case strings.HasPrefix(expr, "/") || expr == "":
+		return normalizeJSONPointer(expr)
+	default:
+		return "", fmt.Errorf("path must start with '$' (JSONPath) or '/' (JSON Pointer): %q", expr)
+	}
+}
+
+func normalizeJSONPath(expr string) (string, error) {
+	rest := strings.TrimPrefix(expr, "$")
+	if rest == "" {
+		return "$", nil
+	}
+
+	matches := jsonPathSegment.FindAllStringSubmatchIndex(rest, -1)
+	if matches == nil {
+		return "", fmt.Errorf("invalid JSONPath expression: %q", expr)
+	}
+
+	var consumed int
+	var b strings.Builder
+	b.WriteString("$")
+	for _, m := range matches {
+		if m[0] != consumed {
+			return "", fmt.Errorf("invalid JSONPath expression: %q", expr)
+		}
+		if m[2] != -1 {
+			b.WriteString("[")
+			b.WriteString(rest[m[2]:m[3]])
+			b.WriteString("]")
+		} else {
+			b.WriteString(".")
+			b.WriteString(rest[m[4]:m[5]])
+		}
+		consumed = m[1]
+	}
+	if consumed != len(rest) {
+		return "", fmt.Errorf("invalid JSONPath expression: %q", expr)
+	}
+
+	return b.String(), nil
+}
+
+func normalizeJSONPointer(expr string) (string, error) {
+	if expr == "" {
+		return "$", nil
+	}
+
+	var b strings.Builder
+	b.WriteString("$")
+	for _, tok := range strings.Split(strings.TrimPrefix(expr, "/"), "/") {
+		tok = strings.ReplaceAll(tok, "~1", "/")
+		tok = strings.ReplaceAll(tok, "~0", "~")
+
+		if arrayIndex.MatchString(tok) {
+			b.WriteString("[")
+			b.WriteString(tok)
+			b.WriteString("]")
+		} else {
+			b.WriteString(".")
+			b.WriteString(tok)
+		}
+	}
+
+	return b.String(), nil
+}
+
+var arrayIndex = regexp.MustCompile(`^\d+$`)
+
+// pathMatches reports whether a node's canonical path satisfies expr. A
+// trailing "*" on expr matches it as a prefix, so a single JSONPath can
+// select every element under an array or object.
+func pathMatches(nodePath, expr string) bool {
+	if strings.HasSuffix(expr, "*") {
+		return strings.HasPrefix(nodePath, strings.TrimSuffix(expr, "*"))
+	}
+	return nodePath == expr
+}
+
+// GotoPrompt shows the navigation bar input (bound to ':' and 'g') that
+// feeds Goto.
+func (g *Gui) GotoPrompt() {
+	pageName := "goto"
+	if g.Pages.HasPage(pageName) {
+		g.Pages.ShowPage(pageName)
+		return
+	}
+
+	input := tview.NewInputField()
+	input.SetBorder(true).SetTitle("goto (JSONPath or JSON Pointer)").SetTitleAlign(tview.AlignLeft)
+	input.SetLabel(":").SetLabelWidth(2).SetDoneFunc(func(key tcell.Key) {
+		g.Pages.RemovePage(pageName)
+		if key != tcell.KeyEnter {
+			return
+		}
+		if err := g.Goto(input.GetText()); err != nil {
+			g.Message(err.Error(), "main", func() {})
+		}
+	})
+
+	g.Pages.AddAndSwitchToPage(pageName, g.Modal(input, 0, 3), true).ShowPage("main")
+}
+
+// Goto jumps the tree cursor to the node matching expr, a JSONPath
+// expression or JSON Pointer. Ancestors of the match are expanded. If expr
+// matches multiple nodes (e.g. via a trailing "*"), the tree is filtered
+// down to the matches the same way Search renders its results.
+func (g *Gui) Goto(expr string) error {
+	path, err := normalizePath(expr)
+	if err != nil {
+		return err
+	}
+
+	matches := g.Tree.FindByPath(path)
+	if len(matches) == 0 {
+		return fmt.Errorf("no node matches %q", expr)
+	}
+
+	if len(matches) > 1 {
+		// Copy before mutating: GetRoot() and OriginRoot are the same node
+		// until something has already filtered the view, so setting its
+		// children in place would permanently replace the real tree with
+		// the match list (see gui.go's Search, which takes the same copy).
+		root := *g.Tree.OriginRoot
+		g.Tree.SetRoot(&root)
+		root.SetChildren(matches)
+		return nil
+	}
+
+	g.expandAncestors(matches[0])
+	g.Tree.SetCurrentNode(matches[0])
+	return nil
+}
+
+// expandAncestors expands every ancestor of target so it's visible in the
+// rendered tree. Since tview.TreeNode doesn't expose parent links, it walks
+// down from the root following the node's own path prefixes.
+func (g *Gui) expandAncestors(target *tview.TreeNode) {
+	targetRef, _ := target.GetReference().(Reference)
+	g.expandTo(g.Tree.GetRoot(), targetRef.Path)
+}
+
+func (g *Gui) expandTo(node *tview.TreeNode, path string) bool {
+	ref, _ := node.GetReference().(Reference)
+	if ref.Path == path {
+		return true
+	}
+
+	// Only descend into node if it could actually be an ancestor of path; a
+	// not-yet-materialized Object/Array only has a "loading..." placeholder
+	// child until expanded, so expand it on demand rather than walking past
+	// it and reporting no ancestor found.
+	if !pathCouldContain(ref.Path, path) {
+		return false
+	}
+	if (ref.JSONType == Object || ref.JSONType == Array) && !ref.Loaded {
+		if err := g.Tree.expand(node); err != nil {
+			log.Println(fmt.Sprintf("can't expand %s while navigating to %q: %s", ref.Path, path, err))
+			return false
+		}
+	}
+
+	for _, child := range node.GetChildren() {
+		if g.expandTo(child, path) {
+			node.SetExpanded(true)
+			return true
+		}
+	}
+
+	return false
+}
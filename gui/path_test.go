@@ -0,0 +1,75 @@
+package gui
+
+import "testing"
+
+func TestNormalizePath(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "$.foo.bar[2]", want: "$.foo.bar[2]"},
+		{in: "/foo/bar/2", want: "$.foo.bar[2]"},
+		{in: "", want: "$"},
+		{in: "/", want: "$."},
+		{in: "foo", wantErr: true},
+		{in: "$.foo[bad]", wantErr: true},
+	}
+
+	for _, c := range cases {
+		got, err := normalizePath(c.in)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("normalizePath(%q): expected error, got %q", c.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("normalizePath(%q): unexpected error: %s", c.in, err)
+		}
+		if got != c.want {
+			t.Errorf("normalizePath(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestPathMatches(t *testing.T) {
+	if !pathMatches("$.foo.bar", "$.foo.bar") {
+		t.Error("exact path should match")
+	}
+	if pathMatches("$.foo.bar", "$.foo.baz") {
+		t.Error("different paths should not match")
+	}
+	if !pathMatches("$.items[0]", "$.items*") {
+		t.Error("trailing '*' should match as a prefix")
+	}
+	if pathMatches("$.other[0]", "$.items*") {
+		t.Error("trailing '*' should not match an unrelated prefix")
+	}
+}
+
+// TestGotoMultiMatchDoesNotMutateOriginRoot guards against Goto filtering
+// the tree in place via the same *tview.TreeNode GetRoot() and OriginRoot
+// share until something has already copied the root (see the comment in
+// Goto). A regression here silently discards the real tree on the first
+// multi-match Goto.
+func TestGotoMultiMatchDoesNotMutateOriginRoot(t *testing.T) {
+	g := New()
+	doc := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"id": float64(1)},
+			map[string]interface{}{"id": float64(2)},
+		},
+	}
+	g.Tree.UpdateView(g, doc)
+
+	wantChildren := len(g.Tree.OriginRoot.GetChildren())
+
+	if err := g.Goto("$.items*"); err != nil {
+		t.Fatalf("Goto: %v", err)
+	}
+
+	if got := len(g.Tree.OriginRoot.GetChildren()); got != wantChildren {
+		t.Fatalf("OriginRoot mutated by Goto: got %d children, want %d", got, wantChildren)
+	}
+}
@@ -0,0 +1,331 @@
+package gui
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// LoadSchemaPrompt asks for a schema file and passes it to LoadSchema.
+func (g *Gui) LoadSchemaPrompt() {
+	labels := []string{"file"}
+	g.Form(labels, "load", "load JSON schema", "load_schema", 7, func(values map[string]string) error {
+		return g.LoadSchema(values[labels[0]])
+	})
+}
+
+// LoadSchema compiles the JSON Schema (Draft 2020-12) at path and
+// validates the current tree against it, coloring non-conforming nodes
+// red; no separate status glyph is drawn. Validation is re-run after every
+// command, undo, redo and subtree expansion.
+func (g *Gui) LoadSchema(path string) error {
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+
+	schema, err := compiler.Compile(path)
+	if err != nil {
+		log.Println(fmt.Sprintf("can't compile schema: %s", err))
+		return err
+	}
+
+	g.schema = schema
+	g.validateSchema()
+	return nil
+}
+
+// validateSchema re-validates the current tree against g.schema and
+// decorates every non-conforming node in red. It's a no-op if no schema
+// has been loaded.
+func (g *Gui) validateSchema() {
+	g.schemaErrors = make(map[string]string)
+	if g.schema == nil {
+		return
+	}
+
+	if err := g.schema.Validate(g.makeJSON(g.Tree.GetRoot())); err != nil {
+		if verr, ok := err.(*jsonschema.ValidationError); ok {
+			collectSchemaErrors(verr, g.schemaErrors)
+		}
+	}
+
+	decorateSchemaErrors(g.Tree, g.schemaErrors)
+}
+
+func collectSchemaErrors(verr *jsonschema.ValidationError, out map[string]string) {
+	if len(verr.Causes) == 0 {
+		out[verr.InstanceLocation] = verr.Message
+		return
+	}
+	for _, cause := range verr.Causes {
+		collectSchemaErrors(cause, out)
+	}
+}
+
+// decorateSchemaErrors colors the node at each error's path red. Like
+// colorDiff/markConflicts, it resolves each path through Tree.FindByPath
+// instead of walking the whole tree, so validating a schema only
+// materializes the handful of nodes that actually failed validation, not
+// every collapsed subtree along the way.
+func decorateSchemaErrors(t *Tree, errs map[string]string) {
+	for pointer := range errs {
+		path, err := normalizePath(pointer)
+		if err != nil {
+			log.Println(fmt.Sprintf("can't normalize schema error path %q: %s", pointer, err))
+			continue
+		}
+
+		for _, node := range t.FindByPath(path) {
+			node.SetColor(tcell.ColorRed)
+		}
+	}
+}
+
+// schemaErrorFor returns the validation message for node's path, if any.
+// Tree.SetKeybindings' focus handler uses this to populate the status
+// line when a node is highlighted.
+func (g *Gui) schemaErrorFor(node *tview.TreeNode) (string, bool) {
+	if g.schema == nil {
+		return "", false
+	}
+	ref, _ := node.GetReference().(Reference)
+	msg, ok := g.schemaErrors[jsonPathToPointer(ref.Path)]
+	return msg, ok
+}
+
+// schemaProperties returns g.schema's top-level properties in a stable
+// order. Resolving the subschema for an arbitrarily nested node (through
+// $ref/allOf/oneOf) is out of scope here, so schema-driven forms only
+// apply at the document root.
+func (g *Gui) schemaProperties() []string {
+	if g.schema == nil || g.schema.Properties == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(g.schema.Properties))
+	for name := range g.schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (g *Gui) isRequired(name string) bool {
+	for _, r := range g.schema.Required {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AddValueWithSchema builds an add-value form with one field per top-level
+// schema property instead of a single raw-JSON field, enforcing each
+// property's type/enum/pattern and offering autocomplete for enum values.
+// It falls back to the plain AddValue form when no schema is loaded.
+func (g *Gui) AddValueWithSchema() {
+	pageName := "add_value_schema"
+	names := g.schemaProperties()
+	if names == nil {
+		g.AddValue()
+		return
+	}
+
+	if g.Pages.HasPage(pageName) {
+		g.Pages.ShowPage(pageName)
+		return
+	}
+
+	form := tview.NewForm()
+	for _, name := range names {
+		input := tview.NewInputField()
+		if options := enumOptions(g.schema.Properties[name]); options != nil {
+			input.SetAutocompleteFunc(func(current string) []string {
+				return matchingOptions(options, current)
+			})
+		}
+		form.AddFormItem(input.SetLabel(name).SetLabelWidth(0))
+	}
+
+	form.AddButton("add", func() {
+		obj := make(map[string]interface{})
+
+		for _, name := range names {
+			item := form.GetFormItemByLabel(name).(*tview.InputField)
+			text := item.GetText()
+			if text == "" && !g.isRequired(name) {
+				continue
+			}
+
+			v, err := coerceSchemaValue(g.schema.Properties[name], text)
+			if err != nil {
+				g.Message(fmt.Sprintf("%s: %s", name, err), pageName, func() {})
+				return
+			}
+			obj[name] = v
+		}
+
+		current := g.Tree.GetCurrentNode()
+		currentRef, _ := current.GetReference().(Reference)
+
+		children := g.Tree.AddNode(obj)
+		values := make([]interface{}, len(children))
+		for idx, n := range children {
+			values[idx] = nodeToJSON(n)
+		}
+
+		if err := g.runCommand(&addNodesCommand{
+			parent:   current,
+			children: children,
+			values:   values,
+			path:     jsonPathToPointer(currentRef.Path),
+		}); err != nil {
+			g.Message(err.Error(), pageName, func() {})
+			return
+		}
+
+		g.Pages.RemovePage(pageName)
+	}).
+		AddButton("cancel", func() {
+			g.Pages.RemovePage(pageName)
+		})
+
+	form.SetBorder(true).SetTitle("add value (schema-driven)").SetTitleAlign(tview.AlignLeft)
+
+	g.Pages.AddAndSwitchToPage(pageName, g.Modal(form, 0, 7+len(names)), true).ShowPage("main")
+}
+
+func enumOptions(prop *jsonschema.Schema) []string {
+	if prop == nil || len(prop.Enum) == 0 {
+		return nil
+	}
+
+	options := make([]string, 0, len(prop.Enum))
+	for _, e := range prop.Enum {
+		options = append(options, fmt.Sprintf("%v", e))
+	}
+	return options
+}
+
+func matchingOptions(options []string, current string) []string {
+	var matches []string
+	for _, opt := range options {
+		if current == "" || bytes.HasPrefix([]byte(opt), []byte(current)) {
+			matches = append(matches, opt)
+		}
+	}
+	return matches
+}
+
+// coerceSchemaValue converts a form field's raw text into the Go value its
+// schema property expects, validating type/enum/pattern along the way.
+func coerceSchemaValue(prop *jsonschema.Schema, text string) (interface{}, error) {
+	if prop == nil {
+		return text, nil
+	}
+
+	if len(prop.Enum) > 0 {
+		valid := false
+		for _, e := range prop.Enum {
+			if fmt.Sprintf("%v", e) == text {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("must be one of %v", prop.Enum)
+		}
+	}
+
+	if prop.Pattern != nil && !prop.Pattern.MatchString(text) {
+		return nil, fmt.Errorf("must match pattern %s", prop.Pattern.String())
+	}
+
+	for _, t := range prop.Types {
+		switch t {
+		case "integer", "number":
+			buf := bytes.NewBufferString(text)
+			v, err := UnMarshalJSON(buf)
+			if err != nil {
+				return nil, fmt.Errorf("must be a %s", t)
+			}
+			f, ok := v.(float64)
+			if !ok {
+				return nil, fmt.Errorf("must be a %s", t)
+			}
+			if t == "integer" && f != math.Trunc(f) {
+				return nil, fmt.Errorf("must be an integer")
+			}
+			return f, nil
+		case "boolean":
+			buf := bytes.NewBufferString(text)
+			v, err := UnMarshalJSON(buf)
+			if err != nil {
+				return nil, fmt.Errorf("must be a boolean")
+			}
+			if _, ok := v.(bool); !ok {
+				return nil, fmt.Errorf("must be a boolean")
+			}
+			return v, nil
+		}
+	}
+
+	return text, nil
+}
+
+// GenerateFromSchema inserts a skeleton object under the current node
+// honoring g.schema's required top-level fields, leaving optional fields
+// out so the user fills in only what's needed.
+func (g *Gui) GenerateFromSchema() error {
+	if g.schema == nil {
+		return fmt.Errorf("no schema loaded")
+	}
+
+	skeleton := make(map[string]interface{})
+	for _, name := range g.schema.Required {
+		prop := g.schema.Properties[name]
+		skeleton[name] = zeroValueFor(prop)
+	}
+
+	current := g.Tree.GetCurrentNode()
+	currentRef, _ := current.GetReference().(Reference)
+
+	children := g.Tree.AddNode(skeleton)
+	values := make([]interface{}, len(children))
+	for idx, n := range children {
+		values[idx] = nodeToJSON(n)
+	}
+
+	return g.runCommand(&addNodesCommand{
+		parent:   current,
+		children: children,
+		values:   values,
+		path:     jsonPathToPointer(currentRef.Path),
+	})
+}
+
+func zeroValueFor(prop *jsonschema.Schema) interface{} {
+	if prop == nil || len(prop.Types) == 0 {
+		return ""
+	}
+
+	switch prop.Types[0] {
+	case "object":
+		return map[string]interface{}{}
+	case "array":
+		return []interface{}{}
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return false
+	case "null":
+		return nil
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,45 @@
+package gui
+
+import (
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// TestCoerceSchemaValueEnforcesType guards against coerceSchemaValue
+// accepting any value that merely parses as valid JSON (e.g. a quoted
+// string, a bool, or null) for a property typed "integer"/"number"/
+// "boolean" instead of checking the decoded Go value's actual kind.
+func TestCoerceSchemaValueEnforcesType(t *testing.T) {
+	intProp := &jsonschema.Schema{Types: []string{"integer"}}
+
+	for _, text := range []string{`"hello"`, "true", "null", "3.5", "[1,2]"} {
+		if _, err := coerceSchemaValue(intProp, text); err == nil {
+			t.Errorf("coerceSchemaValue(integer, %q): expected error, got none", text)
+		}
+	}
+
+	v, err := coerceSchemaValue(intProp, "42")
+	if err != nil {
+		t.Fatalf("coerceSchemaValue(integer, %q): unexpected error: %s", "42", err)
+	}
+	if v != float64(42) {
+		t.Errorf("coerceSchemaValue(integer, %q) = %v, want 42", "42", v)
+	}
+
+	boolProp := &jsonschema.Schema{Types: []string{"boolean"}}
+
+	for _, text := range []string{"1", `"true"`, "null", `{"a":1}`} {
+		if _, err := coerceSchemaValue(boolProp, text); err == nil {
+			t.Errorf("coerceSchemaValue(boolean, %q): expected error, got none", text)
+		}
+	}
+
+	v, err = coerceSchemaValue(boolProp, "true")
+	if err != nil {
+		t.Fatalf("coerceSchemaValue(boolean, %q): unexpected error: %s", "true", err)
+	}
+	if v != true {
+		t.Errorf("coerceSchemaValue(boolean, %q) = %v, want true", "true", v)
+	}
+}
@@ -0,0 +1,393 @@
+package gui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// JSONType describes the kind of JSON construct a tree node represents.
+type JSONType int
+
+const (
+	Object JSONType = iota
+	Array
+	Key
+	Value
+)
+
+// ValueType describes the Go type backing a Value node's text.
+type ValueType int
+
+const (
+	String ValueType = iota
+	Int
+	Float
+	Boolean
+	Null
+)
+
+// Reference is stored via TreeNode.SetReference/GetReference and lets the
+// rest of the package recover the JSON semantics of a rendered node.
+type Reference struct {
+	JSONType  JSONType
+	ValueType ValueType
+
+	// Path is the node's canonical JSON path from the tree root, e.g.
+	// "$.foo.bar[2].baz". It is (re)computed by Tree.assignPaths whenever
+	// the tree is built or mutated.
+	Path string
+
+	// The following three fields support lazy loading of Object/Array
+	// subtrees (see lazy.go). Pending holds a not-yet-materialized
+	// node's decoded value; Loaded reports whether its real children
+	// have been built; SpillPath is set when a materialized subtree was
+	// evicted to a tempfile to stay within the memory budget.
+	Pending   interface{}
+	Loaded    bool
+	SpillPath string
+}
+
+// Tree renders a JSON document as a tview.TreeView. OriginRoot keeps the
+// unfiltered root around so Search can restore the full tree once a query
+// is cleared. Object/Array subtrees are materialized lazily (see lazy.go)
+// and tracked by lazy so memory stays within TSON_MEMORYLIMIT.
+type Tree struct {
+	*tview.TreeView
+	OriginRoot *tview.TreeNode
+
+	lazy *lazyStore
+}
+
+func NewTree() *Tree {
+	return &Tree{
+		TreeView: tview.NewTreeView(),
+		lazy:     newLazyStore(),
+	}
+}
+
+// UpdateView rebuilds the tree from scratch for the given decoded JSON value.
+func (t *Tree) UpdateView(g *Gui, i interface{}) {
+	root := NewRootTreeNode(i)
+	root.SetChildren(t.AddNode(i))
+
+	t.SetRoot(root)
+	t.SetCurrentNode(root)
+	t.OriginRoot = root
+
+	g.rebuildIndex()
+}
+
+// assignPaths walks node and its children, stamping each Reference.Path
+// with its canonical JSON path from root. The key's path segment is
+// appended when descending into a Key node, and its single value child
+// gets that same path (Key nodes themselves are never matched by
+// FindByPath), so the value doesn't repeat the segment.
+func (t *Tree) assignPaths(node *tview.TreeNode, path string) {
+	ref, _ := node.GetReference().(Reference)
+	ref.Path = path
+	node.SetReference(ref)
+
+	children := node.GetChildren()
+	for i, child := range children {
+		childRef, _ := child.GetReference().(Reference)
+
+		switch {
+		case ref.JSONType == Key:
+			t.assignPaths(child, path)
+		case childRef.JSONType == Key:
+			t.assignPaths(child, fmt.Sprintf("%s.%s", path, child.GetText()))
+		default:
+			t.assignPaths(child, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+// FindByPath returns the nodes whose canonical path matches expr, which
+// must already be in the internal "$.foo.bar[2]" form produced by
+// assignPaths (see normalizePath for JSONPath/JSON Pointer parsing).
+func (t *Tree) FindByPath(expr string) []*tview.TreeNode {
+	root := t.GetRoot()
+	if root == nil {
+		return nil
+	}
+
+	var matches []*tview.TreeNode
+	t.collectByPath(root, expr, &matches)
+	return matches
+}
+
+func (t *Tree) collectByPath(node *tview.TreeNode, expr string, matches *[]*tview.TreeNode) {
+	ref, _ := node.GetReference().(Reference)
+	if ref.JSONType != Key && pathMatches(ref.Path, expr) {
+		*matches = append(*matches, node)
+	}
+
+	// Prune: nothing under node can match expr unless node is an ancestor
+	// of (or, for a wildcard expr, a descendant of) the path being searched
+	// for, so don't force-materialize branches the search can't reach.
+	if !pathCouldContain(ref.Path, expr) {
+		return
+	}
+
+	// A not-yet-materialized Object/Array only has a "loading..." placeholder
+	// child, so expand it before descending or nothing under it is ever found.
+	if (ref.JSONType == Object || ref.JSONType == Array) && !ref.Loaded {
+		if err := t.expand(node); err != nil {
+			log.Println(fmt.Sprintf("can't expand %s while searching for %q: %s", ref.Path, expr, err))
+			return
+		}
+	}
+
+	for _, child := range node.GetChildren() {
+		t.collectByPath(child, expr, matches)
+	}
+}
+
+// pathCouldContain reports whether a node at nodePath could contain, or be
+// contained by, the path(s) expr refers to. expr may end in "*" (see
+// pathMatches), so a trailing "*" is trimmed before comparing.
+func pathCouldContain(nodePath, expr string) bool {
+	trimmed := strings.TrimSuffix(expr, "*")
+	return strings.HasPrefix(trimmed, nodePath) || strings.HasPrefix(nodePath, trimmed)
+}
+
+// NewRootTreeNode builds the single node representing i itself, without
+// recursing into its children.
+func NewRootTreeNode(i interface{}) *tview.TreeNode {
+	node := tview.NewTreeNode("root").
+		SetColor(tcell.ColorGreen).
+		SetExpanded(true)
+
+	switch v := i.(type) {
+	case map[string]interface{}:
+		node.SetReference(Reference{JSONType: Object, Loaded: true})
+	case []interface{}:
+		node.SetReference(Reference{JSONType: Array, Loaded: true})
+	default:
+		node.SetReference(Reference{JSONType: Value, ValueType: valueType(v)})
+	}
+
+	return node
+}
+
+// AddNode turns a decoded JSON value into the slice of child nodes that
+// should be attached under its parent in the tree. It only builds one
+// level: Object/Array children come back as lazy placeholders (see
+// newValueNode) that materialize their own children on first expansion.
+func (t *Tree) AddNode(i interface{}) []*tview.TreeNode {
+	var nodes []*tview.TreeNode
+
+	switch v := i.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			keyNode := tview.NewTreeNode(key).
+				SetReference(Reference{JSONType: Key})
+			keyNode.AddChild(newValueNode(val))
+			nodes = append(nodes, keyNode)
+		}
+	case []interface{}:
+		for _, val := range v {
+			nodes = append(nodes, newValueNode(val))
+		}
+	}
+
+	return nodes
+}
+
+// newValueNode builds the node for a single JSON value. Object/Array
+// values are not expanded into real children yet: they get a single
+// "loading" placeholder so the tree still shows them as expandable, and
+// keep the decoded value in Reference.Pending until Tree.expand runs.
+func newValueNode(i interface{}) *tview.TreeNode {
+	switch v := i.(type) {
+	case map[string]interface{}:
+		node := tview.NewTreeNode("{...}").
+			SetReference(Reference{JSONType: Object, Pending: v}).
+			SetExpanded(false)
+		node.AddChild(placeholderNode())
+		return node
+	case []interface{}:
+		node := tview.NewTreeNode("[...]").
+			SetReference(Reference{JSONType: Array, Pending: v}).
+			SetExpanded(false)
+		node.AddChild(placeholderNode())
+		return node
+	default:
+		return tview.NewTreeNode(fmt.Sprintf("%v", v)).
+			SetReference(Reference{JSONType: Value, ValueType: valueType(v)})
+	}
+}
+
+func placeholderNode() *tview.TreeNode {
+	return tview.NewTreeNode("loading...").SetSelectable(false)
+}
+
+// expand materializes node's real children from its pending (or spilled)
+// value, tracking it in the tree's lazyStore so it can be evicted again
+// once the memory budget is exceeded.
+func (t *Tree) expand(node *tview.TreeNode) error {
+	ref, _ := node.GetReference().(Reference)
+	if ref.Loaded {
+		return nil
+	}
+
+	value, err := pendingValue(ref)
+	if err != nil {
+		return err
+	}
+
+	node.SetChildren(t.AddNode(value))
+	t.assignPaths(t.GetRoot(), "$")
+
+	if ref.SpillPath != "" {
+		os.Remove(ref.SpillPath)
+	}
+	ref.Pending = nil
+	ref.SpillPath = ""
+	ref.Loaded = true
+	node.SetReference(ref)
+
+	if size, err := jsonSize(value); err == nil {
+		t.lazy.touch(node, size)
+	}
+
+	return nil
+}
+
+// pendingValue recovers an unloaded node's value, rehydrating it from its
+// spill file if it was evicted, or returning its in-memory Pending value
+// if it was never expanded at all.
+func pendingValue(ref Reference) (interface{}, error) {
+	if ref.SpillPath == "" {
+		return ref.Pending, nil
+	}
+
+	b, err := ioutil.ReadFile(ref.SpillPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func jsonSize(v interface{}) (int64, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(b)), nil
+}
+
+func valueType(i interface{}) ValueType {
+	switch i.(type) {
+	case float64:
+		return Float
+	case int, int64:
+		return Int
+	case bool:
+		return Boolean
+	case nil:
+		return Null
+	default:
+		return String
+	}
+}
+
+// SetKeybindings wires up the global keyboard shortcuts for the tree view.
+func (t *Tree) SetKeybindings(g *Gui) {
+	t.SetSelectedFunc(func(node *tview.TreeNode) {
+		ref, _ := node.GetReference().(Reference)
+		if (ref.JSONType == Object || ref.JSONType == Array) && !ref.Loaded {
+			if err := t.expand(node); err != nil {
+				log.Println(fmt.Sprintf("can't expand node: %s", err))
+				return
+			}
+			// expand only materializes node's children; the search index and
+			// schema decoration built over the old (placeholder) subtree are
+			// now stale for everything underneath it.
+			g.rebuildIndex()
+			g.validateSchema()
+		}
+		node.SetExpanded(!node.IsExpanded())
+	})
+
+	t.SetChangedFunc(func(node *tview.TreeNode) {
+		if msg, bad := g.schemaErrorFor(node); bad {
+			g.StatusBar.SetText(msg)
+		} else {
+			g.StatusBar.SetText("")
+		}
+	})
+
+	t.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyCtrlZ:
+			if err := g.Undo(); err != nil && err != ErrNoHistory {
+				g.Message(err.Error(), "main", func() {})
+			}
+			return nil
+		case tcell.KeyCtrlR:
+			if err := g.Redo(); err != nil && err != ErrNoHistory {
+				g.Message(err.Error(), "main", func() {})
+			}
+			return nil
+		}
+
+		switch event.Rune() {
+		case 'l':
+			g.LoadJSON()
+			return nil
+		case 'w':
+			g.SaveJSON()
+			return nil
+		case '/':
+			g.Search()
+			return nil
+		case ':', 'g':
+			g.GotoPrompt()
+			return nil
+		case 'a':
+			g.AddNode()
+			return nil
+		case 'A':
+			g.AddValue()
+			return nil
+		case 'd':
+			g.DiffJSONPrompt()
+			return nil
+		case 'p':
+			g.SaveJSONPatchPrompt()
+			return nil
+		case 'P':
+			g.ApplyJSONPatchPrompt()
+			return nil
+		case 'm':
+			g.MergeJSONPrompt()
+			return nil
+		case 's':
+			g.LoadSchemaPrompt()
+			return nil
+		case 'V':
+			g.AddValueWithSchema()
+			return nil
+		case 'G':
+			if err := g.GenerateFromSchema(); err != nil {
+				g.Message(err.Error(), "main", func() {})
+			}
+			return nil
+		}
+		return event
+	})
+}
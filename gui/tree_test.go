@@ -0,0 +1,88 @@
+package gui
+
+import "testing"
+
+// TestCollectByPathExpandsLazySubtree guards against FindByPath only seeing
+// whatever happens to already be expanded: "outer" is a lazy placeholder
+// until something expands it, so a naive walk would never reach "inner". It
+// also checks that reaching "outer.inner" doesn't force-expand the sibling
+// "decoy" subtree, which pathCouldContain should prune from the walk.
+func TestCollectByPathExpandsLazySubtree(t *testing.T) {
+	g := New()
+	doc := map[string]interface{}{
+		"outer": map[string]interface{}{
+			"inner": "findme",
+		},
+		"decoy": map[string]interface{}{
+			"other": "untouched",
+		},
+	}
+	g.Tree.UpdateView(g, doc)
+
+	matches := g.Tree.FindByPath("$.outer.inner")
+	if len(matches) != 1 {
+		t.Fatalf("expected 1 match under not-yet-expanded %q, got %d", "outer", len(matches))
+	}
+	if matches[0].GetText() != "findme" {
+		t.Errorf("got %q, want %q", matches[0].GetText(), "findme")
+	}
+
+	root := g.Tree.GetRoot()
+	if ref, _ := valueChild(root, "decoy").GetReference().(Reference); ref.Loaded {
+		t.Error(`"decoy" should stay a lazy placeholder: finding "$.outer.inner" shouldn't expand unrelated subtrees`)
+	}
+}
+
+func TestPathCouldContain(t *testing.T) {
+	cases := []struct {
+		nodePath, expr string
+		want           bool
+	}{
+		{"$", "$.a.b", true},
+		{"$.a", "$.a.b", true},
+		{"$.a.b", "$.a.b", true},
+		{"$.a.b.c", "$.a.b", true},
+		{"$.x", "$.a.b", false},
+		{"$", "$.items*", true},
+		{"$.items", "$.items*", true},
+		{"$.items[0]", "$.items*", true},
+		{"$.other", "$.items*", false},
+	}
+
+	for _, c := range cases {
+		if got := pathCouldContain(c.nodePath, c.expr); got != c.want {
+			t.Errorf("pathCouldContain(%q, %q) = %v, want %v", c.nodePath, c.expr, got, c.want)
+		}
+	}
+}
+
+// TestLazyStoreEvictsLeastRecentlyTouched exercises touch's eviction path
+// directly: once usage exceeds budget, the least-recently-touched node is
+// spilled and collapsed back to a single placeholder child.
+func TestLazyStoreEvictsLeastRecentlyTouched(t *testing.T) {
+	s := newLazyStore()
+	s.budget = 10
+
+	older := newValueNode(map[string]interface{}{"a": "1"})
+	older.SetReference(Reference{JSONType: Object, Loaded: true, Path: "$.older"})
+	older.SetChildren(NewTree().AddNode(map[string]interface{}{"a": "1"}))
+	s.touch(older, 8)
+
+	newer := newValueNode(map[string]interface{}{"b": "2"})
+	newer.SetReference(Reference{JSONType: Object, Loaded: true, Path: "$.newer"})
+	newer.SetChildren(NewTree().AddNode(map[string]interface{}{"b": "2"}))
+	s.touch(newer, 8)
+
+	olderRef, _ := older.GetReference().(Reference)
+	if olderRef.Loaded {
+		t.Fatal("expected older entry to be evicted once budget was exceeded")
+	}
+	if len(older.GetChildren()) != 1 || older.GetChildren()[0].GetText() != "loading..." {
+		t.Error("expected evicted node to be collapsed back to a single placeholder child")
+	}
+
+	newerRef, _ := newer.GetReference().(Reference)
+	if !newerRef.Loaded {
+		t.Error("expected newer entry to remain loaded")
+	}
+}
@@ -0,0 +1,219 @@
+package gui
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/rivo/tview"
+)
+
+// ErrNoHistory is returned by Executor.Undo/Redo when there's nothing to
+// undo or redo.
+var ErrNoHistory = errors.New("no history")
+
+// undoLogSuffix names the persistent change log kept next to a loaded
+// file, so a crashed session's in-flight edits can be replayed the next
+// time that file is opened.
+const undoLogSuffix = ".tson-log.ndjson"
+
+// Command is a single undoable mutation. AddNode and AddValue build one
+// per invocation and run it through Gui.Executor instead of mutating the
+// tree directly, so Do/Undo only ever touch the nodes they recorded
+// rather than re-walking the whole tree.
+type Command interface {
+	Do() error
+	Undo() error
+	// Patches returns the RFC 6902 operations this command's Do performs,
+	// for the persistent change log.
+	Patches() []PatchOp
+}
+
+// Executor runs Commands and keeps a bounded undo/redo history for Tree's
+// Ctrl-Z/Ctrl-R keybindings. Every applied command is also appended to an
+// on-disk ndjson patch log (see openLog) so a crash can be recovered from.
+type Executor struct {
+	capacity int
+	undo     []Command
+	redo     []Command
+
+	logPath string
+	logFile *os.File
+}
+
+func NewExecutor(capacity int) *Executor {
+	return &Executor{capacity: capacity}
+}
+
+// Do runs cmd, pushes it onto the undo stack (evicting the oldest entry
+// once capacity is exceeded), clears the redo stack, and appends cmd's
+// patch to the change log.
+func (e *Executor) Do(cmd Command) error {
+	if err := cmd.Do(); err != nil {
+		return err
+	}
+
+	e.undo = append(e.undo, cmd)
+	if len(e.undo) > e.capacity {
+		e.undo = e.undo[len(e.undo)-e.capacity:]
+	}
+	e.redo = nil
+
+	e.appendLog(cmd.Patches())
+	return nil
+}
+
+// Undo reverts the most recently applied command and moves it to the
+// redo stack.
+func (e *Executor) Undo() error {
+	if len(e.undo) == 0 {
+		return ErrNoHistory
+	}
+
+	cmd := e.undo[len(e.undo)-1]
+	e.undo = e.undo[:len(e.undo)-1]
+
+	if err := cmd.Undo(); err != nil {
+		return err
+	}
+
+	e.redo = append(e.redo, cmd)
+	return nil
+}
+
+// Redo re-applies the most recently undone command.
+func (e *Executor) Redo() error {
+	if len(e.redo) == 0 {
+		return ErrNoHistory
+	}
+
+	cmd := e.redo[len(e.redo)-1]
+	e.redo = e.redo[:len(e.redo)-1]
+
+	if err := cmd.Do(); err != nil {
+		return err
+	}
+
+	e.undo = append(e.undo, cmd)
+	e.appendLog(cmd.Patches())
+	return nil
+}
+
+// Reset discards the undo/redo history, for when the tree it refers to is
+// about to be replaced wholesale (e.g. LoadJSON): a stale command's parent
+// node belongs to a now-detached tree, so leaving it in history would make
+// Undo/Redo silently no-op on the new document instead of reporting
+// ErrNoHistory.
+func (e *Executor) Reset() {
+	e.undo = nil
+	e.redo = nil
+}
+
+// openLog closes any previously open change log and starts a new one
+// next to sourcePath. Passing an empty sourcePath just closes the log
+// (e.g. nothing is loaded yet).
+func (e *Executor) openLog(sourcePath string) {
+	e.closeLog()
+	if sourcePath == "" {
+		return
+	}
+
+	e.logPath = sourcePath + undoLogSuffix
+	f, err := os.OpenFile(e.logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		log.Println(fmt.Sprintf("can't open change log %s: %s", e.logPath, err))
+		return
+	}
+	e.logFile = f
+}
+
+func (e *Executor) closeLog() {
+	if e.logFile != nil {
+		e.logFile.Close()
+		e.logFile = nil
+	}
+	e.logPath = ""
+}
+
+func (e *Executor) appendLog(ops []PatchOp) {
+	if e.logFile == nil {
+		return
+	}
+
+	enc := json.NewEncoder(e.logFile)
+	for _, op := range ops {
+		if err := enc.Encode(op); err != nil {
+			log.Println(fmt.Sprintf("can't append to change log: %s", err))
+			return
+		}
+	}
+}
+
+// recoverChangeLog reads a leftover ndjson patch log for sourcePath (from
+// a session that crashed before closing it cleanly), if one exists. It
+// returns a nil slice and no error when there's nothing to recover.
+func recoverChangeLog(sourcePath string) ([]PatchOp, error) {
+	path := sourcePath + undoLogSuffix
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var ops []PatchOp
+	dec := json.NewDecoder(bytes.NewReader(b))
+	for {
+		var op PatchOp
+		if err := dec.Decode(&op); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+
+	return ops, nil
+}
+
+// addNodesCommand adds one or more sibling nodes under parent. It records
+// parent's child count before Do, which Undo truncates back to, and the
+// decoded values used to build the nodes, which Patches logs.
+type addNodesCommand struct {
+	parent   *tview.TreeNode
+	children []*tview.TreeNode
+	values   []interface{}
+	path     string // JSON Pointer of parent, for the patch log
+
+	before int // parent's child count before Do, for Undo
+}
+
+func (c *addNodesCommand) Do() error {
+	c.before = len(c.parent.GetChildren())
+	for _, n := range c.children {
+		c.parent.AddChild(n)
+	}
+	return nil
+}
+
+func (c *addNodesCommand) Undo() error {
+	children := c.parent.GetChildren()
+	c.parent.SetChildren(children[:c.before])
+	return nil
+}
+
+func (c *addNodesCommand) Patches() []PatchOp {
+	ops := make([]PatchOp, len(c.values))
+	for i, v := range c.values {
+		ops[i] = PatchOp{Op: "add", Path: c.path + "/-", Value: v}
+	}
+	return ops
+}
@@ -0,0 +1,127 @@
+package gui
+
+import (
+	"testing"
+
+	"github.com/rivo/tview"
+)
+
+func TestExecutorUndoRedo(t *testing.T) {
+	parent := tview.NewTreeNode("parent")
+	exec := NewExecutor(10)
+
+	cmd := &addNodesCommand{
+		parent:   parent,
+		children: []*tview.TreeNode{tview.NewTreeNode("child")},
+		values:   []interface{}{"child"},
+		path:     "/parent",
+	}
+
+	if err := exec.Do(cmd); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if got := len(parent.GetChildren()); got != 1 {
+		t.Fatalf("after Do: %d children, want 1", got)
+	}
+
+	if err := exec.Undo(); err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	if got := len(parent.GetChildren()); got != 0 {
+		t.Fatalf("after Undo: %d children, want 0", got)
+	}
+	if err := exec.Undo(); err != ErrNoHistory {
+		t.Fatalf("Undo with empty history: got %v, want ErrNoHistory", err)
+	}
+
+	if err := exec.Redo(); err != nil {
+		t.Fatalf("Redo: %v", err)
+	}
+	if got := len(parent.GetChildren()); got != 1 {
+		t.Fatalf("after Redo: %d children, want 1", got)
+	}
+	if err := exec.Redo(); err != ErrNoHistory {
+		t.Fatalf("Redo with empty history: got %v, want ErrNoHistory", err)
+	}
+}
+
+// TestExecutorDoClearsRedoStack checks that a fresh Do after an Undo
+// discards the redo stack, so a later Redo can't re-apply the undone
+// command on top of the new one.
+func TestExecutorDoClearsRedoStack(t *testing.T) {
+	parent := tview.NewTreeNode("parent")
+	exec := NewExecutor(10)
+
+	first := &addNodesCommand{parent: parent, children: []*tview.TreeNode{tview.NewTreeNode("a")}, values: []interface{}{"a"}, path: "/parent"}
+	second := &addNodesCommand{parent: parent, children: []*tview.TreeNode{tview.NewTreeNode("b")}, values: []interface{}{"b"}, path: "/parent"}
+
+	if err := exec.Do(first); err != nil {
+		t.Fatalf("Do(first): %v", err)
+	}
+	if err := exec.Undo(); err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	if err := exec.Do(second); err != nil {
+		t.Fatalf("Do(second): %v", err)
+	}
+
+	if err := exec.Redo(); err != ErrNoHistory {
+		t.Fatalf("Redo after a fresh Do should have no history, got %v", err)
+	}
+	if got := len(parent.GetChildren()); got != 1 {
+		t.Fatalf("got %d children, want 1", got)
+	}
+}
+
+// TestExecutorReset guards the bug LoadJSON's stale history would otherwise
+// hit: after loading a new document, a leftover undo entry referencing the
+// old tree's nodes must be gone rather than silently no-op-ing on Undo.
+func TestExecutorReset(t *testing.T) {
+	parent := tview.NewTreeNode("parent")
+	exec := NewExecutor(10)
+
+	cmd := &addNodesCommand{
+		parent:   parent,
+		children: []*tview.TreeNode{tview.NewTreeNode("child")},
+		values:   []interface{}{"child"},
+		path:     "/parent",
+	}
+	if err := exec.Do(cmd); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if err := exec.Undo(); err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	if err := exec.Redo(); err != nil {
+		t.Fatalf("Redo: %v", err)
+	}
+
+	exec.Reset()
+
+	if err := exec.Undo(); err != ErrNoHistory {
+		t.Fatalf("Undo after Reset: got %v, want ErrNoHistory", err)
+	}
+	if err := exec.Redo(); err != ErrNoHistory {
+		t.Fatalf("Redo after Reset: got %v, want ErrNoHistory", err)
+	}
+}
+
+func TestAddNodesCommandPatches(t *testing.T) {
+	parent := tview.NewTreeNode("parent")
+	cmd := &addNodesCommand{
+		parent:   parent,
+		children: []*tview.TreeNode{tview.NewTreeNode("a"), tview.NewTreeNode("b")},
+		values:   []interface{}{"a", "b"},
+		path:     "/items",
+	}
+
+	ops := cmd.Patches()
+	if len(ops) != 2 {
+		t.Fatalf("got %d patch ops, want 2", len(ops))
+	}
+	for i, op := range ops {
+		if op.Op != "add" || op.Path != "/items/-" || op.Value != cmd.values[i] {
+			t.Errorf("unexpected patch op %d: %+v", i, op)
+		}
+	}
+}